@@ -0,0 +1,162 @@
+package es
+
+import (
+	"context"
+	"fmt"
+	"github.com/elastic/go-elasticsearch/v7/esutil"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+type (
+	// BulkOptions configures the esutil.BulkIndexer backing BulkStore.
+	BulkOptions struct {
+		NumWorkers    int
+		FlushBytes    int
+		FlushInterval time.Duration
+
+		// OnSuccess/OnFailure, when set, are invoked for every item in
+		// addition to the aggregate BulkStats returned by BulkStore.
+		OnSuccess func(doc User)
+		OnFailure func(doc User, err error)
+	}
+
+	// BulkStats aggregates the outcome of a BulkStore call.
+	BulkStats struct {
+		Indexed  uint64
+		Failed   uint64
+		Bytes    uint64
+		Duration time.Duration
+	}
+)
+
+var (
+	bulkIndexedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "go_elastic",
+		Subsystem: "bulk",
+		Name:      "indexed_total",
+		Help:      "Total number of documents successfully indexed via BulkStore.",
+	})
+	bulkFailedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "go_elastic",
+		Subsystem: "bulk",
+		Name:      "failed_total",
+		Help:      "Total number of documents that failed to index via BulkStore.",
+	})
+	bulkBytesTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "go_elastic",
+		Subsystem: "bulk",
+		Name:      "bytes_total",
+		Help:      "Total number of document bytes successfully indexed via BulkStore.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(bulkIndexedTotal, bulkFailedTotal, bulkBytesTotal)
+}
+
+// BulkStore indexes docs read off the channel using esutil.BulkIndexer,
+// batching instead of issuing one synchronous IndexRequest per document.
+// Retrying on 429/503 is handled beneath esutil.BulkIndexer by the
+// underlying esconn.Connection's transport, so the connection passed to
+// es.New must set esconn.Config.RetryOnStatus to include 429 (and 503, if
+// the default 502/503/504 set isn't already in play) for that to apply
+// here. The channel is expected to be closed by the caller once all
+// documents have been sent; BulkStore returns once it has been drained
+// and flushed.
+func (c *Client) BulkStore(ctx context.Context, index string, docs <-chan User, opts BulkOptions) (BulkStats, error) {
+	if index == "" {
+		index = c.defaultIndex
+	}
+
+	if opts.NumWorkers <= 0 {
+		opts.NumWorkers = 4
+	}
+	if opts.FlushBytes <= 0 {
+		opts.FlushBytes = 5 * 1024 * 1024
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = 30 * time.Second
+	}
+
+	var stats BulkStats
+
+	bi, err := esutil.NewBulkIndexer(esutil.BulkIndexerConfig{
+		Index:         index,
+		Client:        c.esClient,
+		NumWorkers:    opts.NumWorkers,
+		FlushBytes:    opts.FlushBytes,
+		FlushInterval: opts.FlushInterval,
+		OnError: func(_ context.Context, err error) {
+			c.log.Error("es.Client.BulkStore() bulk indexer error", zap.Error(err))
+		},
+	})
+	if err != nil {
+		return stats, fmt.Errorf("es.client.BulkStore(): error creating bulk indexer: %w", err)
+	}
+
+	start := time.Now()
+
+	for doc := range docs {
+		doc := doc
+
+		docB, err := jsoniter.Marshal(doc)
+		if err != nil {
+			atomic.AddUint64(&stats.Failed, 1)
+			bulkFailedTotal.Inc()
+			if opts.OnFailure != nil {
+				opts.OnFailure(doc, err)
+			}
+			continue
+		}
+
+		err = bi.Add(ctx, esutil.BulkIndexerItem{
+			Action: "index",
+			Body:   strings.NewReader(string(docB)),
+			OnSuccess: func(_ context.Context, _ esutil.BulkIndexerItem, _ esutil.BulkIndexerResponseItem) {
+				atomic.AddUint64(&stats.Indexed, 1)
+				atomic.AddUint64(&stats.Bytes, uint64(len(docB)))
+				bulkIndexedTotal.Inc()
+				bulkBytesTotal.Add(float64(len(docB)))
+				if opts.OnSuccess != nil {
+					opts.OnSuccess(doc)
+				}
+			},
+			OnFailure: func(_ context.Context, _ esutil.BulkIndexerItem, _ esutil.BulkIndexerResponseItem, err error) {
+				atomic.AddUint64(&stats.Failed, 1)
+				bulkFailedTotal.Inc()
+				if opts.OnFailure != nil {
+					opts.OnFailure(doc, err)
+				}
+			},
+		})
+		if err != nil {
+			atomic.AddUint64(&stats.Failed, 1)
+			bulkFailedTotal.Inc()
+			if opts.OnFailure != nil {
+				opts.OnFailure(doc, err)
+			}
+		}
+	}
+
+	if err = bi.Close(ctx); err != nil {
+		return stats, fmt.Errorf("es.client.BulkStore(): error closing bulk indexer: %w", err)
+	}
+
+	stats.Duration = time.Since(start)
+
+	biStats := bi.Stats()
+	c.log.Debug("es.Client.BulkStore() finished",
+		zap.Uint64("indexed", stats.Indexed),
+		zap.Uint64("failed", stats.Failed),
+		zap.Uint64("bytes", stats.Bytes),
+		zap.Duration("duration", stats.Duration),
+		zap.Uint64("numFlushed", biStats.NumFlushed),
+	)
+
+	return stats, nil
+}
@@ -7,17 +7,18 @@ import (
 	"github.com/elastic/go-elasticsearch/v7"
 	"github.com/elastic/go-elasticsearch/v7/esapi"
 	jsoniter "github.com/json-iterator/go"
+	"go-elastic/esconn"
 	"go-elastic/logger"
 	"go.uber.org/zap"
 	"log"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 )
 
 type (
 	Config struct {
-		Hosts                 []string
 		DefaultIndex          string
 		DisableCompression    bool
 		MaxSearchQueryTimeout time.Duration
@@ -27,11 +28,15 @@ type (
 
 	Client struct {
 		log                   *logger.Logger
-		esCfg                 elasticsearch.Config
+		conn                  *esconn.Connection
 		esClient              *elasticsearch.Client
 		defaultIndex          string
 		maxSearchQueryTimeout time.Duration
 		isTrackTotalHits      bool
+		// ServerMajor is the detected Elasticsearch major version (e.g. 7
+		// for 7.x), used to branch on request-body differences between
+		// majors (the index mapping schema).
+		ServerMajor int
 	}
 
 	User struct {
@@ -43,33 +48,87 @@ type (
 	SearchResult struct {
 		Users      []User
 		TotalCount int64
-		LastSort   float64
+		LastSort   []interface{}
 	}
 )
 
-func New(log *logger.Logger, esCfg elasticsearch.Config, customCfg Config) (*Client, error) {
-	es, err := elasticsearch.NewClient(esCfg)
+const (
+	minSupportedServerMajor = 6
+	maxSupportedServerMajor = 8
+)
+
+// New builds a search client on top of an already-established connection.
+// Transport, auth and retry concerns live in conn; New only wires up the
+// search-specific defaults (default index, query timeout, total-hits
+// tracking) and detects the cluster's major version, which CreateIndex uses
+// to adapt the mapping schema. Load decodes hits.total by its JSON shape,
+// not by the detected version.
+func New(log *logger.Logger, conn *esconn.Connection, customCfg Config) (*Client, error) {
+	log.Info("Try to create defaultIndex (if not exist)")
+
+	serverMajor, err := detectServerMajor(conn)
 	if err != nil {
-		log.Info("Could not create new ElasticSearch client due error")
-		return nil, err
+		return nil, fmt.Errorf("es.New(): error detecting server version: %w", err)
 	}
 
-	log.Info("Successfully create new ElasticSearch client.")
-	log.Sugar().Info("Successfully connected to AWS OpenSearch (Elasticsearch) cluster. Hosts: %v", customCfg.Hosts)
-	log.Info("Try to create defaultIndex (if not exist)")
+	if serverMajor < minSupportedServerMajor || serverMajor > maxSupportedServerMajor {
+		return nil, fmt.Errorf("es.New(): unsupported Elasticsearch major version: %d", serverMajor)
+	}
+
+	if serverMajor < 7 {
+		log.Sugar().Warnf("Elasticsearch %d.x is deprecated, please upgrade to 7.x or 8.x", serverMajor)
+	}
 
 	c := &Client{
 		log:                   log,
-		esCfg:                 esCfg,
-		esClient:              es,
+		conn:                  conn,
+		esClient:              conn.ESClient(),
 		defaultIndex:          customCfg.DefaultIndex,
 		maxSearchQueryTimeout: customCfg.MaxSearchQueryTimeout,
 		isTrackTotalHits:      true,
+		ServerMajor:           serverMajor,
 	}
 
 	return c, nil
 }
 
+// detectServerMajor calls the cluster root API and parses version.number
+// into a major version integer (e.g. "7.17.3" -> 7).
+func detectServerMajor(conn *esconn.Connection) (int, error) {
+	res, err := conn.Info(context.Background())
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = res.Body.Close()
+	}()
+
+	if res.IsError() {
+		return 0, fmt.Errorf("cluster root API returned error status: %s", res.Status())
+	}
+
+	var r struct {
+		Version struct {
+			Number string `json:"number"`
+		} `json:"version"`
+	}
+	if err = jsoniter.NewDecoder(res.Body).Decode(&r); err != nil {
+		return 0, fmt.Errorf("error decoding root API response: %w", err)
+	}
+
+	major, _, found := strings.Cut(r.Version.Number, ".")
+	if !found {
+		return 0, fmt.Errorf("unexpected version string: %q", r.Version.Number)
+	}
+
+	n, err := strconv.Atoi(major)
+	if err != nil {
+		return 0, fmt.Errorf("error parsing major version from %q: %w", r.Version.Number, err)
+	}
+
+	return n, nil
+}
+
 func (c *Client) CreateIndex(ctx context.Context, index string, mapping string) error {
 	var file []byte
 	file, err := os.ReadFile(mapping)
@@ -78,7 +137,10 @@ func (c *Client) CreateIndex(ctx context.Context, index string, mapping string)
 			zap.String("path_to_mapping_schema", mapping),
 			zap.Error(err))
 	}
-	indexMappingSchema := string(file)
+	indexMappingSchema, err := c.adaptMappingForVersion(string(file))
+	if err != nil {
+		return fmt.Errorf("err adapting mapping schema for ES %d.x: %v", c.ServerMajor, err)
+	}
 
 	req := esapi.IndicesCreateRequest{
 		Index: index,
@@ -104,12 +166,52 @@ func (c *Client) CreateIndex(ctx context.Context, index string, mapping string)
 	return nil
 }
 
+// adaptMappingForVersion hoists a single "_doc" mapping type out of
+// mappingJSON for ES 7+, which dropped mapping types entirely. Older
+// mapping schemas (written for 6.x) nest their fields under "_doc"; on 6.x
+// the schema is left untouched.
+func (c *Client) adaptMappingForVersion(mappingJSON string) (string, error) {
+	if c.ServerMajor < 7 {
+		return mappingJSON, nil
+	}
+
+	var doc map[string]interface{}
+	if err := jsoniter.Unmarshal([]byte(mappingJSON), &doc); err != nil {
+		return "", fmt.Errorf("error parsing mapping schema: %w", err)
+	}
+
+	mappings, ok := doc["mappings"].(map[string]interface{})
+	if !ok {
+		return mappingJSON, nil
+	}
+
+	docType, ok := mappings["_doc"].(map[string]interface{})
+	if !ok {
+		return mappingJSON, nil
+	}
+
+	doc["mappings"] = docType
+
+	out, err := jsoniter.Marshal(doc)
+	if err != nil {
+		return "", fmt.Errorf("error re-marshaling mapping schema: %w", err)
+	}
+
+	return string(out), nil
+}
+
+// Load fetches a page of users with a User-specific map[string]interface{}
+// decode.
+//
+// Deprecated: use Search[User] instead, which avoids the
+// map[string]interface{} round-trip and works with any query shape, not
+// just match_all.
 func (c *Client) Load(
 	ctx context.Context,
 	index string,
 	from int,
 	size int,
-	cursor float64,
+	cursor []interface{},
 ) (SearchResult, error) {
 	query := map[string]interface{}{
 		"query": map[string]interface{}{
@@ -123,8 +225,8 @@ func (c *Client) Load(
 
 	query["sort"] = sortQuery
 
-	if cursor != 0 {
-		query["search_after"] = []float64{cursor}
+	if len(cursor) != 0 {
+		query["search_after"] = cursor
 	}
 
 	var buf bytes.Buffer
@@ -138,7 +240,7 @@ func (c *Client) Load(
 
 	var res *esapi.Response
 	var err error
-	if cursor != 0 {
+	if len(cursor) != 0 {
 		res, err = c.esClient.Search(
 			c.esClient.Search.WithContext(ctx),
 			c.esClient.Search.WithTimeout(c.maxSearchQueryTimeout),
@@ -203,17 +305,17 @@ func (c *Client) Load(
 
 	result :=
 		func() SearchResult {
-			totalCnt := int64(r["hits"].(map[string]interface{})["total"].(map[string]interface{})["value"].(float64))
+			totalCnt := parseTotalHits(r["hits"].(map[string]interface{})["total"])
 			if totalCnt == 0 {
 				return SearchResult{}
 			}
 
 			cntFind := len(r["hits"].(map[string]interface{})["hits"].([]interface{}))
 			docs := make([]User, 0, cntFind)
-			var lastSort float64
+			var lastSort []interface{}
 
 			for _, v := range r["hits"].(map[string]interface{})["hits"].([]interface{}) {
-				lastSort = v.(map[string]interface{})["sort"].([]interface{})[0].(float64)
+				lastSort = v.(map[string]interface{})["sort"].([]interface{})
 				doc := User{}
 
 				// Why we have double convert from map[string]interface{} -> string -> struct.
@@ -248,6 +350,19 @@ func (c *Client) Load(
 	return result, nil
 }
 
+// parseTotalHits decodes hits.total, which is a plain number on ES 5/6
+// and an object ({"value": N, "relation": "eq"}) on ES 7+.
+func parseTotalHits(total interface{}) int64 {
+	switch t := total.(type) {
+	case float64:
+		return int64(t)
+	case map[string]interface{}:
+		return int64(t["value"].(float64))
+	default:
+		return 0
+	}
+}
+
 func (c *Client) Store(ctx context.Context, index string, doc User) error {
 	defer func() {
 		if r := recover(); r != nil {
@@ -298,19 +413,24 @@ func (c *Client) Store(ctx context.Context, index string, doc User) error {
 }
 
 func (c *Client) Create100kUsers(ctx context.Context, index string) {
-	user := User{
-		ID:        0,
-		CreatedAt: time.Now(),
-		Username:  "init",
-	}
-
-	for i := 0; i < 100000; i++ {
-		user.Username = fmt.Sprintf("%v %v", "user", i)
-		user.ID = i
-		err := c.Store(ctx, index, user)
-		if err != nil {
-			log.Fatal("failed to store", zap.Error(err))
+	docs := make(chan User)
+
+	go func() {
+		defer close(docs)
+		for i := 0; i < 100000; i++ {
+			docs <- User{
+				ID:        i,
+				CreatedAt: time.Now(),
+				Username:  fmt.Sprintf("%v %v", "user", i),
+			}
 		}
+	}()
+
+	stats, err := c.BulkStore(ctx, index, docs, BulkOptions{})
+	if err != nil {
+		log.Fatal("failed to bulk store", zap.Error(err))
 	}
 
+	c.log.Sugar().Infof("Create100kUsers finished: indexed=%d failed=%d bytes=%d duration=%s",
+		stats.Indexed, stats.Failed, stats.Bytes, stats.Duration)
 }
@@ -0,0 +1,246 @@
+// Package eventsink turns an Elasticsearch connection into a reusable sink
+// for services that want to ship structured events to ES without writing
+// their own bulk/queueing code: events are bulk-indexed via esutil, routed
+// to a per-event index, and spilled to an on-disk queue (and replayed from
+// it on the next reconnect) whenever the cluster can't keep up.
+package eventsink
+
+import (
+	"context"
+	"fmt"
+	"github.com/elastic/go-elasticsearch/v7/esutil"
+	jsoniter "github.com/json-iterator/go"
+	"go-elastic/esconn"
+	"go-elastic/logger"
+	"go.uber.org/zap"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// Event is a single structured record to ship to Elasticsearch.
+	Event struct {
+		Timestamp time.Time
+		Payload   map[string]interface{}
+	}
+
+	// IndexFn decides which index an event should be routed to, e.g. a
+	// date-rolled index name like "events-2024.01.02".
+	IndexFn func(Event) string
+
+	// Sink is the minimal interface event producers depend on, so they
+	// can be swapped to another backend in tests without pulling in
+	// Elasticsearch.
+	Sink interface {
+		Send(ctx context.Context, event Event) error
+		Close() error
+	}
+
+	// Config configures a Sink built by NewSink.
+	Config struct {
+		IndexFn IndexFn
+
+		// QueueDir, if set, enables store-and-forward: events that fail
+		// to flush are appended to a queue file under this directory and
+		// replayed the next time NewSink is called. Leaving it empty
+		// disables the on-disk queue - failed events are only logged.
+		QueueDir      string
+		MaxQueueBytes int64
+
+		NumWorkers    int
+		FlushBytes    int
+		FlushInterval time.Duration
+	}
+
+	bulkSink struct {
+		log     *logger.Logger
+		indexFn IndexFn
+		bi      esutil.BulkIndexer
+		queue   *diskQueue
+	}
+)
+
+// DateIndex returns an IndexFn that routes events to prefix + the event's
+// timestamp formatted as "2006.01.02", e.g. DateIndex("events-") yields
+// "events-2024.01.02".
+func DateIndex(prefix string) IndexFn {
+	return func(e Event) string {
+		return prefix + e.Timestamp.Format("2006.01.02")
+	}
+}
+
+// NewSink builds a Sink backed by conn. It replays the events in sealed
+// queue files under cfg.QueueDir (rotated out, or sealed by Close, in a
+// previous run) before returning, so a restart doesn't lose events that
+// failed to flush last time. An active queue file left unsealed is reopened
+// for appending and is not replayed until it is sealed.
+func NewSink(ctx context.Context, log *logger.Logger, conn *esconn.Connection, cfg Config) (Sink, error) {
+	if cfg.IndexFn == nil {
+		cfg.IndexFn = DateIndex("events-")
+	}
+	if cfg.NumWorkers <= 0 {
+		cfg.NumWorkers = 4
+	}
+	if cfg.FlushBytes <= 0 {
+		cfg.FlushBytes = 5 * 1024 * 1024
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = 5 * time.Second
+	}
+
+	queue, err := newDiskQueue(cfg.QueueDir, cfg.MaxQueueBytes)
+	if err != nil {
+		return nil, fmt.Errorf("eventsink.NewSink(): error opening queue: %w", err)
+	}
+
+	s := &bulkSink{
+		log:     log,
+		indexFn: cfg.IndexFn,
+		queue:   queue,
+	}
+
+	bi, err := esutil.NewBulkIndexer(esutil.BulkIndexerConfig{
+		Client:        conn.ESClient(),
+		NumWorkers:    cfg.NumWorkers,
+		FlushBytes:    cfg.FlushBytes,
+		FlushInterval: cfg.FlushInterval,
+		OnError: func(_ context.Context, err error) {
+			log.Error("eventsink.Sink bulk indexer error", zap.Error(err))
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("eventsink.NewSink(): error creating bulk indexer: %w", err)
+	}
+	s.bi = bi
+
+	if err = s.queue.Replay(func(events []Event) error {
+		return s.sendBatch(ctx, events)
+	}); err != nil {
+		return nil, fmt.Errorf("eventsink.NewSink(): error replaying queued events: %w", err)
+	}
+
+	return s, nil
+}
+
+// Send routes event to its index (via the configured IndexFn) and submits
+// it to the bulk indexer. Submission failures and per-item flush failures
+// are spilled to the on-disk queue for a later replay rather than being
+// returned to the caller - Send only fails when the event can't be
+// persisted anywhere. With no QueueDir configured, a failed event is only
+// logged and Send still returns nil.
+func (s *bulkSink) Send(ctx context.Context, event Event) error {
+	return s.send(ctx, event, nil)
+}
+
+// sendBatch submits every event in events to the bulk indexer without
+// waiting in between, so they can still land in the same esutil flush
+// batch, then blocks once until all of them have been confirmed (flushed,
+// or re-queued after a failed flush). Replay uses this to know it's safe to
+// drop a sealed queue file once every event read from it has actually been
+// handled - without forcing one flush round-trip per event, which would
+// otherwise bound replay of a backlog of N events to N * FlushInterval.
+func (s *bulkSink) sendBatch(ctx context.Context, events []Event) error {
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+
+	wg.Add(len(events))
+	for _, event := range events {
+		_ = s.send(ctx, event, func(err error) {
+			if err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+			}
+			wg.Done()
+		})
+	}
+	wg.Wait()
+
+	return firstErr
+}
+
+// send submits event to the bulk indexer. Submission failures and per-item
+// flush failures are spilled to the on-disk queue for a later replay rather
+// than being returned to the caller - send only fails (and its return error
+// and onFlushed, if set, both report it) when the event could be neither
+// flushed nor queued, i.e. truly lost. With the queue disabled (no
+// QueueDir), queuing always succeeds by dropping the event, so such a loss
+// is not reported. onFlushed, when set, is called
+// exactly once, synchronously for a failure send can resolve immediately,
+// or asynchronously once the bulk indexer confirms the flush outcome.
+func (s *bulkSink) send(ctx context.Context, event Event, onFlushed func(error)) error {
+	body, err := marshalEvent(event)
+	if err != nil {
+		if onFlushed != nil {
+			onFlushed(err)
+		}
+		return fmt.Errorf("eventsink.Sink.Send(): error marshaling event: %w", err)
+	}
+
+	err = s.bi.Add(ctx, esutil.BulkIndexerItem{
+		Action: "index",
+		Index:  s.indexFn(event),
+		Body:   strings.NewReader(body),
+		OnSuccess: func(_ context.Context, _ esutil.BulkIndexerItem, _ esutil.BulkIndexerResponseItem) {
+			if onFlushed != nil {
+				onFlushed(nil)
+			}
+		},
+		OnFailure: func(_ context.Context, _ esutil.BulkIndexerItem, _ esutil.BulkIndexerResponseItem, err error) {
+			s.log.Error("eventsink.Sink flush failure, queuing event for replay", zap.Error(err))
+			qerr := s.queue.Enqueue(event)
+			if qerr != nil {
+				s.log.Error("eventsink.Sink failed to queue event", zap.Error(qerr))
+			}
+			if onFlushed != nil {
+				onFlushed(qerr)
+			}
+		},
+	})
+	if err != nil {
+		s.log.Error("eventsink.Sink failed to submit event, queuing for replay", zap.Error(err))
+		qerr := s.queue.Enqueue(event)
+		if onFlushed != nil {
+			onFlushed(qerr)
+		}
+		if qerr != nil {
+			return fmt.Errorf("eventsink.Sink.Send(): error queuing event after submit failure: %w", qerr)
+		}
+	}
+
+	return nil
+}
+
+// marshalEvent encodes event as the document body sent to Elasticsearch,
+// merging in event.Timestamp under "@timestamp" so IndexFn implementations
+// that route on the timestamp (e.g. DateIndex) don't leave it un-indexed
+// unless the caller happens to duplicate it inside Payload.
+func marshalEvent(event Event) (string, error) {
+	doc := make(map[string]interface{}, len(event.Payload)+1)
+	for k, v := range event.Payload {
+		doc[k] = v
+	}
+	doc["@timestamp"] = event.Timestamp
+
+	b, err := jsoniter.Marshal(doc)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// Close flushes any buffered events and seals the on-disk queue so the next
+// NewSink replays whatever didn't make it out.
+func (s *bulkSink) Close() error {
+	if err := s.bi.Close(context.Background()); err != nil {
+		return fmt.Errorf("eventsink.Sink.Close(): error closing bulk indexer: %w", err)
+	}
+
+	return s.queue.Close()
+}
@@ -0,0 +1,76 @@
+package eventsink
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/elastic/go-elasticsearch/v7/esutil"
+	"go-elastic/logger"
+)
+
+// fakeBulkIndexer resolves every Add() synchronously against a caller-supplied
+// per-item outcome, so sendBatch's wg/error-aggregation logic can be tested
+// without a real Elasticsearch cluster.
+type fakeBulkIndexer struct {
+	// fail, if set, is returned (via OnFailure) for every item instead of
+	// calling OnSuccess.
+	fail error
+}
+
+func (f *fakeBulkIndexer) Add(ctx context.Context, item esutil.BulkIndexerItem) error {
+	if f.fail != nil {
+		item.OnFailure(ctx, item, esutil.BulkIndexerResponseItem{}, f.fail)
+		return nil
+	}
+	item.OnSuccess(ctx, item, esutil.BulkIndexerResponseItem{})
+	return nil
+}
+
+func (f *fakeBulkIndexer) Close(context.Context) error    { return nil }
+func (f *fakeBulkIndexer) Stats() esutil.BulkIndexerStats { return esutil.BulkIndexerStats{} }
+
+func newTestSink(t *testing.T, bi esutil.BulkIndexer, queueDir string) *bulkSink {
+	t.Helper()
+
+	log, err := logger.New(logger.Config{Level: "error", Encoding: "json", Outputs: []string{"stdout"}}, "test", "eventsink-test", "0")
+	if err != nil {
+		t.Fatalf("logger.New() error: %v", err)
+	}
+
+	queue, err := newDiskQueue(queueDir, 0)
+	if err != nil {
+		t.Fatalf("newDiskQueue() error: %v", err)
+	}
+
+	return &bulkSink{log: log, indexFn: DateIndex("events-"), bi: bi, queue: queue}
+}
+
+func TestSendBatchAllSucceed(t *testing.T) {
+	s := newTestSink(t, &fakeBulkIndexer{}, "")
+
+	events := []Event{
+		{Payload: map[string]interface{}{"n": float64(1)}},
+		{Payload: map[string]interface{}{"n": float64(2)}},
+	}
+	if err := s.sendBatch(context.Background(), events); err != nil {
+		t.Fatalf("sendBatch() error = %v, want nil", err)
+	}
+}
+
+func TestSendBatchFlushFailureIsRequeuedNotReturned(t *testing.T) {
+	dir := t.TempDir()
+	s := newTestSink(t, &fakeBulkIndexer{fail: errors.New("simulated flush failure")}, dir)
+
+	events := []Event{
+		{Payload: map[string]interface{}{"n": float64(1)}},
+	}
+
+	// A flush failure that was successfully re-queued to disk must not be
+	// reported as an error from sendBatch - otherwise replaying an ES outage
+	// that's still ongoing would make NewSink() fail outright instead of
+	// starting up with the backlog left on disk for the next retry.
+	if err := s.sendBatch(context.Background(), events); err != nil {
+		t.Fatalf("sendBatch() error = %v, want nil (flush failure should be re-queued, not surfaced)", err)
+	}
+}
@@ -0,0 +1,200 @@
+package eventsink
+
+import (
+	"bufio"
+	"fmt"
+	jsoniter "github.com/json-iterator/go"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// diskQueue is an append-only, size-rotated JSON-lines queue used to
+// store events that failed to flush to Elasticsearch so they can be
+// replayed on the next reconnect. A zero-value diskQueue (empty dir) is
+// disabled and silently drops Enqueue calls - that's the "no QueueDir
+// configured" case.
+type diskQueue struct {
+	dir      string
+	maxBytes int64
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+func newDiskQueue(dir string, maxBytes int64) (*diskQueue, error) {
+	q := &diskQueue{dir: dir, maxBytes: maxBytes}
+	if !q.enabled() {
+		return q, nil
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("eventsink.newDiskQueue(): error creating queue dir: %w", err)
+	}
+
+	if err := q.openCurrent(); err != nil {
+		return nil, err
+	}
+
+	return q, nil
+}
+
+func (q *diskQueue) enabled() bool {
+	return q.dir != ""
+}
+
+func (q *diskQueue) currentPath() string {
+	return filepath.Join(q.dir, "queue.jsonl")
+}
+
+func (q *diskQueue) openCurrent() error {
+	f, err := os.OpenFile(q.currentPath(), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("eventsink.diskQueue: error opening queue file: %w", err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("eventsink.diskQueue: error statting queue file: %w", err)
+	}
+
+	q.file = f
+	q.size = info.Size()
+	return nil
+}
+
+// Enqueue appends event to the active queue file, then rotates it out of
+// the way if that write pushed it past maxBytes - so the sealed file always
+// contains the event that triggered its rotation, instead of leaving it
+// behind in a fresh active file Replay doesn't look at.
+func (q *diskQueue) Enqueue(event Event) error {
+	if !q.enabled() {
+		return nil
+	}
+
+	b, err := jsoniter.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("eventsink.diskQueue.Enqueue(): error marshaling event: %w", err)
+	}
+	b = append(b, '\n')
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	n, err := q.file.Write(b)
+	if err != nil {
+		return fmt.Errorf("eventsink.diskQueue.Enqueue(): error writing to queue file: %w", err)
+	}
+	q.size += int64(n)
+
+	if q.maxBytes > 0 && q.size > q.maxBytes {
+		if err = q.rotate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// seal closes the active queue file and renames it under a timestamped name
+// so Replay can pick it up, without reopening a new active file. Callers
+// must hold q.mu.
+func (q *diskQueue) seal() error {
+	if err := q.file.Close(); err != nil {
+		return fmt.Errorf("eventsink.diskQueue.seal(): error closing queue file: %w", err)
+	}
+
+	sealed := filepath.Join(q.dir, fmt.Sprintf("queue-%d.jsonl", time.Now().UnixNano()))
+	if err := os.Rename(q.currentPath(), sealed); err != nil {
+		return fmt.Errorf("eventsink.diskQueue.seal(): error sealing queue file: %w", err)
+	}
+
+	return nil
+}
+
+// rotate seals the active queue file, then opens a fresh one so Enqueue can
+// keep appending. Callers must hold q.mu.
+func (q *diskQueue) rotate() error {
+	if err := q.seal(); err != nil {
+		return err
+	}
+
+	return q.openCurrent()
+}
+
+// Replay reads every sealed (rotated) queue file in order and passes all
+// events read from it to sendBatch in a single call, so a backlog doesn't
+// cost one flush round-trip per event. sendBatch must only return once
+// every event it was given has been durably handled (flushed to
+// Elasticsearch, or re-queued elsewhere after a failed flush) - only then
+// is it safe to delete that file without losing anything still in flight. A
+// sendBatch failure stops replay of that file so the remaining events
+// aren't lost.
+func (q *diskQueue) Replay(sendBatch func([]Event) error) error {
+	if !q.enabled() {
+		return nil
+	}
+
+	matches, err := filepath.Glob(filepath.Join(q.dir, "queue-*.jsonl"))
+	if err != nil {
+		return fmt.Errorf("eventsink.diskQueue.Replay(): error listing sealed queue files: %w", err)
+	}
+
+	for _, path := range matches {
+		if err = q.replayFile(path, sendBatch); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (q *diskQueue) replayFile(path string, sendBatch func([]Event) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("eventsink.diskQueue.Replay(): error opening %s: %w", path, err)
+	}
+
+	var events []Event
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var event Event
+		if err = jsoniter.Unmarshal(scanner.Bytes(), &event); err != nil {
+			_ = f.Close()
+			return fmt.Errorf("eventsink.diskQueue.Replay(): error decoding event from %s: %w", path, err)
+		}
+		events = append(events, event)
+	}
+	if err = scanner.Err(); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("eventsink.diskQueue.Replay(): error scanning %s: %w", path, err)
+	}
+	if err = f.Close(); err != nil {
+		return fmt.Errorf("eventsink.diskQueue.Replay(): error closing %s: %w", path, err)
+	}
+
+	if len(events) > 0 {
+		if err = sendBatch(events); err != nil {
+			return fmt.Errorf("eventsink.diskQueue.Replay(): error replaying events from %s: %w", path, err)
+		}
+	}
+
+	return os.Remove(path)
+}
+
+// Close seals the active queue file so the next Replay picks up any events
+// still buffered in it. Unlike rotate, it doesn't reopen a new active file -
+// the queue isn't written to again after Close.
+func (q *diskQueue) Close() error {
+	if !q.enabled() {
+		return nil
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.seal()
+}
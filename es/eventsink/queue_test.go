@@ -0,0 +1,145 @@
+package eventsink
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDiskQueueDisabled(t *testing.T) {
+	q, err := newDiskQueue("", 0)
+	if err != nil {
+		t.Fatalf("newDiskQueue(\"\", 0) error: %v", err)
+	}
+
+	if err = q.Enqueue(Event{}); err != nil {
+		t.Fatalf("Enqueue() on a disabled queue returned error: %v", err)
+	}
+
+	replayed := 0
+	if err = q.Replay(func(events []Event) error { replayed += len(events); return nil }); err != nil {
+		t.Fatalf("Replay() on a disabled queue returned error: %v", err)
+	}
+	if replayed != 0 {
+		t.Fatalf("Replay() on a disabled queue called sendBatch with %d events, want 0", replayed)
+	}
+}
+
+func TestDiskQueueRotateAndReplay(t *testing.T) {
+	dir := t.TempDir()
+
+	q, err := newDiskQueue(dir, 1) // rotate on every Enqueue
+	if err != nil {
+		t.Fatalf("newDiskQueue() error: %v", err)
+	}
+
+	events := []Event{
+		{Payload: map[string]interface{}{"n": float64(1)}},
+		{Payload: map[string]interface{}{"n": float64(2)}},
+		{Payload: map[string]interface{}{"n": float64(3)}},
+	}
+	for _, e := range events {
+		if err = q.Enqueue(e); err != nil {
+			t.Fatalf("Enqueue() error: %v", err)
+		}
+	}
+
+	sealed, err := filepath.Glob(filepath.Join(dir, "queue-*.jsonl"))
+	if err != nil {
+		t.Fatalf("Glob() error: %v", err)
+	}
+	if len(sealed) != len(events) {
+		t.Fatalf("got %d sealed queue files, want %d (each Enqueue should have rotated)", len(sealed), len(events))
+	}
+
+	var replayed []Event
+	if err = q.Replay(func(events []Event) error {
+		replayed = append(replayed, events...)
+		return nil
+	}); err != nil {
+		t.Fatalf("Replay() error: %v", err)
+	}
+
+	if len(replayed) != len(events) {
+		t.Fatalf("replayed %d events, want %d", len(replayed), len(events))
+	}
+
+	remaining, err := filepath.Glob(filepath.Join(dir, "queue-*.jsonl"))
+	if err != nil {
+		t.Fatalf("Glob() error: %v", err)
+	}
+	if len(remaining) != 0 {
+		t.Fatalf("Replay() left %d sealed files behind after every event was sent successfully", len(remaining))
+	}
+}
+
+func TestDiskQueueReplayKeepsFileOnSendFailure(t *testing.T) {
+	dir := t.TempDir()
+
+	q, err := newDiskQueue(dir, 1) // rotate on every Enqueue
+	if err != nil {
+		t.Fatalf("newDiskQueue() error: %v", err)
+	}
+
+	if err = q.Enqueue(Event{Payload: map[string]interface{}{"n": float64(1)}}); err != nil {
+		t.Fatalf("Enqueue() error: %v", err)
+	}
+	if err = q.Enqueue(Event{Payload: map[string]interface{}{"n": float64(2)}}); err != nil {
+		t.Fatalf("Enqueue() error: %v", err)
+	}
+
+	sealedBefore, err := filepath.Glob(filepath.Join(dir, "queue-*.jsonl"))
+	if err != nil {
+		t.Fatalf("Glob() error: %v", err)
+	}
+	if len(sealedBefore) != 2 {
+		t.Fatalf("got %d sealed queue files, want 2", len(sealedBefore))
+	}
+
+	wantErr := errors.New("simulated send failure")
+	err = q.Replay(func([]Event) error { return wantErr })
+	if !errors.Is(err, wantErr) {
+		t.Fatalf("Replay() error = %v, want wrapped %v", err, wantErr)
+	}
+
+	// The file whose send failed (and any after it, since replayFile stops
+	// on the first error) must not be deleted - otherwise the event is lost
+	// for good rather than retried on the next reconnect.
+	sealedAfter, err := filepath.Glob(filepath.Join(dir, "queue-*.jsonl"))
+	if err != nil {
+		t.Fatalf("Glob() error: %v", err)
+	}
+	if len(sealedAfter) != 2 {
+		t.Fatalf("got %d sealed queue files after a failed replay, want 2 (nothing should be removed)", len(sealedAfter))
+	}
+}
+
+func TestDiskQueueCloseSealsActiveFile(t *testing.T) {
+	dir := t.TempDir()
+
+	q, err := newDiskQueue(dir, 0)
+	if err != nil {
+		t.Fatalf("newDiskQueue() error: %v", err)
+	}
+
+	if err = q.Enqueue(Event{Payload: map[string]interface{}{"n": float64(1)}}); err != nil {
+		t.Fatalf("Enqueue() error: %v", err)
+	}
+
+	if err = q.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+
+	if _, err = os.Stat(q.currentPath()); !os.IsNotExist(err) {
+		t.Fatalf("active queue file still present after Close(): err=%v", err)
+	}
+
+	sealed, err := filepath.Glob(filepath.Join(dir, "queue-*.jsonl"))
+	if err != nil {
+		t.Fatalf("Glob() error: %v", err)
+	}
+	if len(sealed) != 1 {
+		t.Fatalf("got %d sealed queue files after Close(), want 1", len(sealed))
+	}
+}
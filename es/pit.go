@@ -0,0 +1,238 @@
+package es
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	jsoniter "github.com/json-iterator/go"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+)
+
+// PageIterator walks a PIT-backed search result set one page at a time,
+// advancing search_after on the hit's full sort tuple so that deep
+// pagination and tied sort values stay stable between pages.
+type PageIterator struct {
+	client      *Client
+	ctx         context.Context
+	index       string
+	pageSize    int
+	sortFields  []map[string]map[string]interface{}
+	keepAlive   string
+	pitID       string
+	searchAfter []interface{}
+	done        bool
+	closed      bool
+}
+
+// LoadWithPIT opens a Point-in-Time against index and returns a PageIterator
+// that streams results page by page via Next(), refreshing the PIT id on
+// every response. Callers must call Close() once done (or early) to release
+// the PIT on the cluster; Close() is also safe to call after the context
+// backing it is cancelled.
+func (c *Client) LoadWithPIT(
+	ctx context.Context,
+	index string,
+	pageSize int,
+	sortFields []map[string]map[string]interface{},
+	pitKeepAlive string,
+) (*PageIterator, error) {
+	if index == "" {
+		index = c.defaultIndex
+	}
+
+	if sortFields == nil {
+		sortFields = []map[string]map[string]interface{}{
+			{"ID": {"order": "asc"}},
+		}
+	}
+
+	pitID, err := c.openPIT(ctx, index, pitKeepAlive)
+	if err != nil {
+		return nil, fmt.Errorf("es.client.LoadWithPIT(): error opening PIT: %w", err)
+	}
+
+	return &PageIterator{
+		client:     c,
+		ctx:        ctx,
+		index:      index,
+		pageSize:   pageSize,
+		sortFields: sortFields,
+		keepAlive:  pitKeepAlive,
+		pitID:      pitID,
+	}, nil
+}
+
+func (c *Client) openPIT(ctx context.Context, index string, keepAlive string) (string, error) {
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		fmt.Sprintf("/%s/_pit?keep_alive=%s", index, keepAlive),
+		nil,
+	)
+	if err != nil {
+		return "", fmt.Errorf("error building open PIT request: %w", err)
+	}
+
+	res, err := c.esClient.Perform(req)
+	if err != nil {
+		return "", fmt.Errorf("error opening PIT: %w", err)
+	}
+	defer func() {
+		if err = res.Body.Close(); err != nil {
+			c.log.Error("es.Client.openPIT() res.Body.Close()", zap.Error(err))
+		}
+	}()
+
+	if res.StatusCode >= 300 {
+		body, _ := io.ReadAll(res.Body)
+		return "", fmt.Errorf("error opening PIT: status %s, body: %s", res.Status, body)
+	}
+
+	var r struct {
+		ID string `json:"id"`
+	}
+	if err = jsoniter.NewDecoder(res.Body).Decode(&r); err != nil {
+		return "", fmt.Errorf("error decoding open PIT response: %w", err)
+	}
+
+	return r.ID, nil
+}
+
+func (c *Client) closePIT(ctx context.Context, pitID string) error {
+	body, err := jsoniter.Marshal(map[string]interface{}{"id": pitID})
+	if err != nil {
+		return fmt.Errorf("error marshaling close PIT body: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, "/_pit", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error building close PIT request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := c.esClient.Perform(req)
+	if err != nil {
+		return fmt.Errorf("error closing PIT: %w", err)
+	}
+	defer func() {
+		if err = res.Body.Close(); err != nil {
+			c.log.Error("es.Client.closePIT() res.Body.Close()", zap.Error(err))
+		}
+	}()
+
+	if res.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("error closing PIT: status %s, body: %s", res.Status, respBody)
+	}
+
+	return nil
+}
+
+// Next fetches the next page of results. It returns an empty, nil slice once
+// the result set is exhausted; callers should stop iterating at that point.
+func (p *PageIterator) Next() ([]User, error) {
+	if p.done {
+		return nil, nil
+	}
+
+	select {
+	case <-p.ctx.Done():
+		return nil, p.ctx.Err()
+	default:
+	}
+
+	query := map[string]interface{}{
+		"query": map[string]interface{}{
+			"match_all": map[string]interface{}{},
+		},
+		"sort": p.sortFields,
+		"pit": map[string]interface{}{
+			"id":         p.pitID,
+			"keep_alive": p.keepAlive,
+		},
+		"size": p.pageSize,
+	}
+
+	if len(p.searchAfter) != 0 {
+		query["search_after"] = p.searchAfter
+	}
+
+	var buf bytes.Buffer
+	if err := jsoniter.NewEncoder(&buf).Encode(query); err != nil {
+		return nil, fmt.Errorf("es.PageIterator.Next(): error encoding query: %w", err)
+	}
+
+	res, err := p.client.esClient.Search(
+		p.client.esClient.Search.WithContext(p.ctx),
+		p.client.esClient.Search.WithTimeout(p.client.maxSearchQueryTimeout),
+		p.client.esClient.Search.WithBody(&buf),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("es.PageIterator.Next(): search response err: %w", err)
+	}
+	defer func() {
+		if err = res.Body.Close(); err != nil {
+			p.client.log.Error("es.PageIterator.Next() res.Body.Close()", zap.Error(err))
+		}
+	}()
+
+	if res.IsError() {
+		body, _ := io.ReadAll(res.Body)
+		return nil, fmt.Errorf("es.PageIterator.Next(): search failure, status: %s, body: %s", res.Status(), body)
+	}
+
+	var r map[string]interface{}
+	if err = jsoniter.NewDecoder(res.Body).Decode(&r); err != nil {
+		return nil, fmt.Errorf("es.PageIterator.Next(): error parsing the response body: %w", err)
+	}
+
+	pit, ok := r["pit_id"].(string)
+	if ok && pit != "" {
+		p.pitID = pit
+	}
+
+	hits := r["hits"].(map[string]interface{})["hits"].([]interface{})
+	if len(hits) == 0 {
+		p.done = true
+		return nil, nil
+	}
+
+	docs := make([]User, 0, len(hits))
+	for _, v := range hits {
+		hit := v.(map[string]interface{})
+		p.searchAfter = hit["sort"].([]interface{})
+
+		doc := User{}
+		jsonBody, err := jsoniter.Marshal(hit["_source"].(map[string]interface{}))
+		if err != nil {
+			return nil, fmt.Errorf("es.PageIterator.Next(): error marshaling _source: %w", err)
+		}
+		if err = jsoniter.Unmarshal(jsonBody, &doc); err != nil {
+			return nil, fmt.Errorf("es.PageIterator.Next(): error unmarshaling into User: %w", err)
+		}
+		docs = append(docs, doc)
+	}
+
+	if len(hits) < p.pageSize {
+		p.done = true
+	}
+
+	return docs, nil
+}
+
+// Close releases the PIT on the cluster. It is safe to call more than once.
+func (p *PageIterator) Close() error {
+	if p.closed {
+		return nil
+	}
+	p.closed = true
+
+	ctx := p.ctx
+	if ctx.Err() != nil {
+		ctx = context.Background()
+	}
+
+	return p.client.closePIT(ctx, p.pitID)
+}
@@ -0,0 +1,167 @@
+package es
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/elastic/go-elasticsearch/v7/esapi"
+	jsoniter "github.com/json-iterator/go"
+	"go.uber.org/zap"
+)
+
+type (
+	// QueryDSL is a single Elasticsearch Query DSL clause, e.g. the value
+	// of the top-level "query" key. It's a thin alias over the map shape
+	// Load already builds queries with, so query construction stays
+	// consistent across the package.
+	QueryDSL map[string]interface{}
+
+	// SearchRequest describes a Search[T] call: what to query, how to
+	// sort/page it, and which aggregations (if any) to compute alongside
+	// the hits.
+	SearchRequest struct {
+		Index       string
+		From        int
+		Size        int
+		Query       QueryDSL
+		Sort        []map[string]map[string]interface{}
+		SearchAfter []interface{}
+		Aggs        map[string]interface{}
+	}
+
+	// TypedResult is the outcome of Search[T]: the page of typed hits, the
+	// cursor to resume from, and any computed aggregations.
+	TypedResult[T any] struct {
+		Hits         []T
+		TotalCount   int64
+		LastSort     []interface{}
+		Aggregations map[string]interface{}
+	}
+
+	esSearchResponse struct {
+		Hits struct {
+			Total interface{} `json:"total"`
+			Hits  []struct {
+				Source json.RawMessage `json:"_source"`
+				Sort   []interface{}   `json:"sort"`
+			} `json:"hits"`
+		} `json:"hits"`
+		Aggregations map[string]interface{} `json:"aggregations"`
+	}
+)
+
+// MatchAll builds a "match_all" QueryDSL clause.
+func MatchAll() QueryDSL {
+	return QueryDSL{"match_all": map[string]interface{}{}}
+}
+
+// Term builds a "term" QueryDSL clause for an exact-value match on field.
+func Term(field string, value interface{}) QueryDSL {
+	return QueryDSL{"term": map[string]interface{}{field: value}}
+}
+
+// Range builds a "range" QueryDSL clause. bounds is passed through as-is,
+// e.g. map[string]interface{}{"gte": 10, "lt": 20}.
+func Range(field string, bounds map[string]interface{}) QueryDSL {
+	return QueryDSL{"range": map[string]interface{}{field: bounds}}
+}
+
+// Bool builds a "bool" QueryDSL clause from its must/should/filter clauses.
+// Any of the slices may be nil.
+func Bool(must, should, filter []QueryDSL) QueryDSL {
+	b := map[string]interface{}{}
+	if len(must) != 0 {
+		b["must"] = must
+	}
+	if len(should) != 0 {
+		b["should"] = should
+	}
+	if len(filter) != 0 {
+		b["filter"] = filter
+	}
+	return QueryDSL{"bool": b}
+}
+
+// Search runs req against the cluster and decodes each hit's _source
+// directly into T, skipping the map[string]interface{} round-trip that
+// Load relies on. It's the typed, generic replacement for Load.
+func Search[T any](ctx context.Context, client *Client, req SearchRequest) (TypedResult[T], error) {
+	index := req.Index
+	if index == "" {
+		index = client.defaultIndex
+	}
+
+	query := map[string]interface{}{}
+
+	if req.Query != nil {
+		query["query"] = map[string]interface{}(req.Query)
+	} else {
+		query["query"] = map[string]interface{}(MatchAll())
+	}
+
+	if len(req.Sort) != 0 {
+		query["sort"] = req.Sort
+	}
+
+	if len(req.SearchAfter) != 0 {
+		query["search_after"] = req.SearchAfter
+	}
+
+	if len(req.Aggs) != 0 {
+		query["aggs"] = req.Aggs
+	}
+
+	var buf bytes.Buffer
+	if err := jsoniter.NewEncoder(&buf).Encode(query); err != nil {
+		return TypedResult[T]{}, fmt.Errorf("es.Search(): error encoding query: %w", err)
+	}
+
+	opts := []func(*esapi.SearchRequest){
+		client.esClient.Search.WithContext(ctx),
+		client.esClient.Search.WithTimeout(client.maxSearchQueryTimeout),
+		client.esClient.Search.WithIndex(index),
+		client.esClient.Search.WithBody(&buf),
+		client.esClient.Search.WithSize(req.Size),
+		client.esClient.Search.WithTrackTotalHits(client.isTrackTotalHits),
+	}
+	if len(req.SearchAfter) == 0 {
+		opts = append(opts, client.esClient.Search.WithFrom(req.From))
+	}
+
+	res, err := client.esClient.Search(opts...)
+	if err != nil {
+		return TypedResult[T]{}, fmt.Errorf("es.Search(): search response err: %w", err)
+	}
+	defer func() {
+		if err = res.Body.Close(); err != nil {
+			client.log.Error("es.Search() res.Body.Close()", zap.Error(err))
+		}
+	}()
+
+	if res.IsError() {
+		return TypedResult[T]{}, fmt.Errorf("es.Search(): search failure, status: %s", res.Status())
+	}
+
+	var r esSearchResponse
+	if err = jsoniter.NewDecoder(res.Body).Decode(&r); err != nil {
+		return TypedResult[T]{}, fmt.Errorf("es.Search(): error decoding response: %w", err)
+	}
+
+	result := TypedResult[T]{
+		TotalCount:   parseTotalHits(r.Hits.Total),
+		Aggregations: r.Aggregations,
+	}
+
+	result.Hits = make([]T, 0, len(r.Hits.Hits))
+	for _, hit := range r.Hits.Hits {
+		var doc T
+		if err = jsoniter.Unmarshal(hit.Source, &doc); err != nil {
+			return TypedResult[T]{}, fmt.Errorf("es.Search(): error unmarshaling _source: %w", err)
+		}
+		result.Hits = append(result.Hits, doc)
+		result.LastSort = hit.Sort
+	}
+
+	return result, nil
+}
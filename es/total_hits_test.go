@@ -0,0 +1,45 @@
+package es
+
+import "testing"
+
+func TestParseTotalHits(t *testing.T) {
+	cases := []struct {
+		name  string
+		total interface{}
+		want  int64
+	}{
+		{
+			name:  "es 5/6 plain number",
+			total: float64(42),
+			want:  42,
+		},
+		{
+			name:  "es 7+ object with eq relation",
+			total: map[string]interface{}{"value": float64(7), "relation": "eq"},
+			want:  7,
+		},
+		{
+			name:  "es 7+ object with gte relation",
+			total: map[string]interface{}{"value": float64(10000), "relation": "gte"},
+			want:  10000,
+		},
+		{
+			name:  "unexpected shape",
+			total: "not a number",
+			want:  0,
+		},
+		{
+			name:  "nil",
+			total: nil,
+			want:  0,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := parseTotalHits(tc.total); got != tc.want {
+				t.Errorf("parseTotalHits(%v) = %d, want %d", tc.total, got, tc.want)
+			}
+		})
+	}
+}
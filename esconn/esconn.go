@@ -0,0 +1,159 @@
+// Package esconn owns the Elasticsearch transport/connection concerns —
+// host/auth wiring, retry and backoff policy, node discovery and the
+// startup ping — so that subsystems built on top of it (the search client
+// in es, the bulk indexer and the event sink) can share one connection
+// instead of each re-building an elasticsearch.Config of their own.
+package esconn
+
+import (
+	"context"
+	"fmt"
+	"github.com/elastic/go-elasticsearch/v7"
+	"github.com/elastic/go-elasticsearch/v7/esapi"
+	"go-elastic/logger"
+	"go.uber.org/zap"
+	"net/http"
+	"time"
+)
+
+type (
+	// Config describes how to reach an Elasticsearch cluster. It mirrors
+	// elasticsearch.Config but stays free of go-elasticsearch types so
+	// callers outside esconn don't need to import it directly.
+	Config struct {
+		Addresses []string
+		Username  string
+		Password  string
+		CloudID   string
+		APIKey    string
+
+		Transport http.RoundTripper
+
+		RetryOnStatus         []int
+		DisableRetry          bool
+		EnableRetryOnTimeout  bool
+		MaxRetries            int
+		RetryBackoff          func(attempt int) time.Duration
+		DiscoverNodesOnStart  bool
+		DiscoverNodesInterval time.Duration
+
+		EnableMetrics     bool
+		EnableDebugLogger bool
+	}
+
+	// OnConnectFunc is invoked once after a Connection has been built and
+	// has successfully pinged the cluster. It's the hook point for
+	// subsystems that need to run setup work (e.g. index bootstrap) as
+	// soon as a connection is available.
+	OnConnectFunc func(*Connection) error
+
+	// Connection owns a single Elasticsearch HTTP client and the node it
+	// was opened against. It knows nothing about search/index/bulk
+	// semantics - that's left to the packages built on top of it.
+	Connection struct {
+		log       *logger.Logger
+		cfg       Config
+		esClient  *elasticsearch.Client
+		onConnect []OnConnectFunc
+	}
+)
+
+// NewConnection builds an Elasticsearch HTTP client from cfg, pings the
+// cluster to make sure it's reachable, and runs any onConnect callbacks.
+func NewConnection(log *logger.Logger, cfg Config, onConnect ...OnConnectFunc) (*Connection, error) {
+	esLogger := logger.NewLoggerForEs(log)
+
+	esCfg := elasticsearch.Config{
+		Addresses:             cfg.Addresses,
+		Username:              cfg.Username,
+		Password:              cfg.Password,
+		CloudID:               cfg.CloudID,
+		APIKey:                cfg.APIKey,
+		RetryOnStatus:         cfg.RetryOnStatus,
+		DisableRetry:          cfg.DisableRetry,
+		EnableRetryOnTimeout:  cfg.EnableRetryOnTimeout,
+		MaxRetries:            cfg.MaxRetries,
+		DiscoverNodesOnStart:  cfg.DiscoverNodesOnStart,
+		DiscoverNodesInterval: cfg.DiscoverNodesInterval,
+		EnableMetrics:         cfg.EnableMetrics,
+		EnableDebugLogger:     cfg.EnableDebugLogger,
+		Transport:             cfg.Transport,
+		Logger:                esLogger,
+	}
+
+	if cfg.RetryBackoff != nil {
+		esCfg.RetryBackoff = cfg.RetryBackoff
+	}
+
+	esClient, err := elasticsearch.NewClient(esCfg)
+	if err != nil {
+		log.Info("Could not create new ElasticSearch client due error")
+		return nil, fmt.Errorf("esconn.NewConnection(): error creating client: %w", err)
+	}
+
+	c := &Connection{
+		log:       log,
+		cfg:       cfg,
+		esClient:  esClient,
+		onConnect: onConnect,
+	}
+
+	if err = c.Ping(context.Background()); err != nil {
+		return nil, fmt.Errorf("esconn.NewConnection(): cluster ping failed: %w", err)
+	}
+
+	log.Info("Successfully connected to Elasticsearch cluster.", zap.Strings("addresses", cfg.Addresses))
+
+	for _, fn := range c.onConnect {
+		if err = fn(c); err != nil {
+			return nil, fmt.Errorf("esconn.NewConnection(): onConnect callback failed: %w", err)
+		}
+	}
+
+	return c, nil
+}
+
+// Ping calls the cluster root API (GET /) to verify that the connection is
+// alive. It only reports success or failure; callers that need fields from
+// the response (e.g. version detection) use Info instead.
+func (c *Connection) Ping(ctx context.Context) (err error) {
+	res, err := c.esClient.Info(c.esClient.Info.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("esconn.Connection.Ping(): error calling root API: %w", err)
+	}
+	defer func() {
+		if cerr := res.Body.Close(); cerr != nil {
+			c.log.Error("esconn.Connection.Ping() res.Body.Close()", zap.Error(cerr))
+		}
+	}()
+
+	if res.IsError() {
+		return fmt.Errorf("esconn.Connection.Ping(): cluster returned error status: %s", res.Status())
+	}
+
+	return nil
+}
+
+// Info returns the raw, undecoded response of the cluster root API (GET /),
+// which callers decode to read fields such as version.number. The caller
+// must close the response body.
+func (c *Connection) Info(ctx context.Context) (*esapi.Response, error) {
+	res, err := c.esClient.Info(c.esClient.Info.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("esconn.Connection.Info(): error calling root API: %w", err)
+	}
+
+	return res, nil
+}
+
+// ESClient exposes the underlying go-elasticsearch client for packages
+// built on top of esconn (search, bulk indexer, event sink) that need to
+// issue requests directly.
+func (c *Connection) ESClient() *elasticsearch.Client {
+	return c.esClient
+}
+
+// Config returns the Config this Connection was built from.
+func (c *Connection) Config() Config {
+	return c.cfg
+}
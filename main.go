@@ -5,8 +5,8 @@ import (
 	"crypto/tls"
 	"encoding/json"
 	"fmt"
-	"github.com/elastic/go-elasticsearch/v7"
 	"go-elastic/es"
+	"go-elastic/esconn"
 	"go-elastic/logger"
 	"log"
 	"net/http"
@@ -46,7 +46,7 @@ func loadSimplePagination(client *es.Client, ctx context.Context, index string)
 	var result []es.User
 
 	for i := from; i < 100; i += size {
-		res, err2 := client.Load(ctx, index, i, size, 0)
+		res, err2 := client.Load(ctx, index, i, size, nil)
 		if err2 != nil {
 			log.Fatalf("failed to fetch results: %v", err2)
 		}
@@ -59,7 +59,7 @@ func loadSimplePagination(client *es.Client, ctx context.Context, index string)
 }
 
 func simpleLoad(client *es.Client, ctx context.Context, index string) es.SearchResult {
-	res, err := client.Load(ctx, index, 0, 10, 0)
+	res, err := client.Load(ctx, index, 0, 10, nil)
 	if err != nil {
 		log.Fatalf("failed to fetch results: %v", err)
 	}
@@ -79,10 +79,9 @@ func pp(data any) {
 func cursorPaginate(client *es.Client, ctx context.Context, index string) []es.User {
 	from := 0
 	size := 10
-	//var cursor float64 = 0
 	var res []es.User
 
-	initRes, _ := client.Load(ctx, index, from, size, 0)
+	initRes, _ := client.Load(ctx, index, from, size, nil)
 	ls := initRes.LastSort
 	res = append(res, initRes.Users...)
 
@@ -100,6 +99,33 @@ func cursorPaginate(client *es.Client, ctx context.Context, index string) []es.U
 	return res
 }
 
+func pitPaginate(client *es.Client, ctx context.Context, index string) []es.User {
+	var res []es.User
+
+	it, err := client.LoadWithPIT(ctx, index, 10, nil, "1m")
+	if err != nil {
+		log.Fatalf("failed to open PIT: %v", err)
+	}
+	defer func() {
+		if err = it.Close(); err != nil {
+			log.Printf("failed to close PIT: %v", err)
+		}
+	}()
+
+	for {
+		users, err2 := it.Next()
+		if err2 != nil {
+			log.Fatalf("failed to fetch page: %v", err2)
+		}
+		if len(users) == 0 {
+			break
+		}
+		res = append(res, users...)
+	}
+
+	return res
+}
+
 func prepareESClient() *es.Client {
 	esHost := "http://localhost:4566/es/us-east-1/my-data"
 	esUsername := ""
@@ -118,8 +144,6 @@ func prepareESClient() *es.Client {
 		panic("logger init error")
 	}
 
-	esLogger := logger.NewLoggerForEs(lg)
-
 	var t http.RoundTripper = &http.Transport{
 		Proxy:                 http.ProxyFromEnvironment, // not so necessary right now, for future.
 		ForceAttemptHTTP2:     false,                     // ?
@@ -136,15 +160,13 @@ func prepareESClient() *es.Client {
 		DisableCompression: true,
 	}
 
-	esCfg := elasticsearch.Config{
+	connCfg := esconn.Config{
 		Addresses:             []string{esHost}, // @see envs in config.conf
 		Username:              esUsername,
 		Password:              esPassword,
 		CloudID:               "",
 		APIKey:                "",
-		Header:                nil,
-		CACert:                nil,
-		RetryOnStatus:         nil, // List of status codes for retry. Default: 502, 503, 504.
+		RetryOnStatus:         []int{429, 502, 503, 504}, // retry on rate-limiting (429) in addition to the default 502/503/504.
 		DisableRetry:          false,
 		EnableRetryOnTimeout:  true,
 		MaxRetries:            3,
@@ -154,9 +176,11 @@ func prepareESClient() *es.Client {
 		EnableDebugLogger:     true,
 		RetryBackoff:          nil,
 		Transport:             t,
-		Logger:                esLogger,
-		Selector:              nil,
-		ConnectionPoolFunc:    nil,
+	}
+
+	conn, err := esconn.NewConnection(lg, connCfg)
+	if err != nil {
+		log.Fatalln("failed to init ES connection")
 	}
 
 	maxTimeoutStr := "30s"
@@ -169,7 +193,7 @@ func prepareESClient() *es.Client {
 		IsTrackTotalHits:      true, // always needed for cnt operations.
 	}
 
-	esClient, err := es.New(lg, esCfg, customStorageCfg)
+	esClient, err := es.New(lg, conn, customStorageCfg)
 	if err != nil {
 		log.Fatalln("failed to init esClient")
 	}